@@ -0,0 +1,352 @@
+package router
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoding names Compress knows how to negotiate. "br" is only ever
+// offered when the binary was built with the "brotli" tag.
+const (
+	encGzip    = "gzip"
+	encDeflate = "deflate"
+	encBrotli  = "br"
+)
+
+// defaultCompressibleTypes are the Content-Types Compress will encode
+// when the caller doesn't pass its own list.
+var defaultCompressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// minCompressSize is the smallest response body Compress will bother
+// encoding; Compress buffers the first write up to this size before
+// deciding, so short responses (and 404s) pass through untouched.
+const minCompressSize = 1024
+
+// compressWriter is satisfied by gzip.Writer, flate.Writer, and
+// (under the "brotli" build tag) brotli.Writer, so Compress can pool
+// and reuse whichever one it picked.
+type compressWriter interface {
+	io.Writer
+	Reset(io.Writer)
+	Flush() error
+	Close() error
+}
+
+type encoderFactory func(level int) (compressWriter, error)
+
+// encoderFactories is the registry of encodings Compress can produce.
+// The "brotli" build tag adds "br" to it from an init() in
+// compress_brotli.go.
+var encoderFactories = map[string]encoderFactory{
+	encGzip:    func(level int) (compressWriter, error) { return gzip.NewWriterLevel(io.Discard, level) },
+	encDeflate: func(level int) (compressWriter, error) { return flate.NewWriter(io.Discard, level) },
+}
+
+// registerEncoding adds an encoding to the registry; it exists so the
+// optional brotli build can plug itself in without compress.go
+// importing it directly.
+func registerEncoding(name string, factory encoderFactory) {
+	encoderFactories[name] = factory
+}
+
+var encoderPools sync.Map // key: "name:level" -> *sync.Pool of compressWriter
+
+func getEncoder(name string, level int) (compressWriter, error) {
+	key := name + ":" + strconv.Itoa(level)
+
+	v, ok := encoderPools.Load(key)
+	if !ok {
+		factory := encoderFactories[name]
+		v, _ = encoderPools.LoadOrStore(key, &sync.Pool{
+			New: func() interface{} {
+				w, err := factory(level)
+				if err != nil {
+					return nil
+				}
+				return w
+			},
+		})
+	}
+
+	pool := v.(*sync.Pool)
+	w, _ := pool.Get().(compressWriter)
+	if w == nil {
+		return encoderFactories[name](level)
+	}
+	return w, nil
+}
+
+func putEncoder(name string, level int, w compressWriter) {
+	key := name + ":" + strconv.Itoa(level)
+	if v, ok := encoderPools.Load(key); ok {
+		v.(*sync.Pool).Put(w)
+	}
+}
+
+// supportedEncodings lists the encodings Compress will offer, in our
+// own preference order (best compression ratio first); only the ones
+// actually registered are included.
+func supportedEncodings() []string {
+	preferred := []string{encBrotli, encGzip, encDeflate}
+	offered := make([]string, 0, len(preferred))
+	for _, name := range preferred {
+		if _, ok := encoderFactories[name]; ok {
+			offered = append(offered, name)
+		}
+	}
+	return offered
+}
+
+type qValue struct {
+	name string
+	q    float64
+}
+
+// parseAcceptEncoding parses an Accept-Encoding header into its
+// (name, q-value) pairs; entries without an explicit q default to 1.
+func parseAcceptEncoding(header string) []qValue {
+	var values []qValue
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, q := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			name = strings.TrimSpace(part[:i])
+			if j := strings.Index(part[i+1:], "q="); j >= 0 {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(part[i+1+j+2:]), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		values = append(values, qValue{name: name, q: q})
+	}
+	return values
+}
+
+// negotiateEncoding picks the best encoding from offered (our own
+// preference order) that the client's Accept-Encoding accepts with a
+// non-zero q-value, preferring higher q-values.
+func negotiateEncoding(header string, offered []string) string {
+	accepted := parseAcceptEncoding(header)
+	if len(accepted) == 0 {
+		return ""
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range offered {
+		for _, a := range accepted {
+			if a.name != enc && a.name != "*" {
+				continue
+			}
+			if a.q > 0 && a.q > bestQ {
+				best, bestQ = enc, a.q
+			}
+		}
+	}
+	return best
+}
+
+// Compress returns middleware that content-negotiates a response
+// encoding against the request's Accept-Encoding (gzip, deflate, and
+// brotli when built with the "brotli" tag), compressing at level only
+// responses whose Content-Type is in types (or defaultCompressibleTypes
+// when types is empty), skipping bodies under 1KB and responses that
+// already carry a Content-Encoding.
+func Compress(level int, types ...string) func(http.Handler) http.Handler {
+	if len(types) == 0 {
+		types = defaultCompressibleTypes
+	}
+	offered := supportedEncodings()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enc := negotiateEncoding(r.Header.Get("Accept-Encoding"), offered)
+			if enc == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{
+				ResponseWriter: w,
+				level:          level,
+				encoding:       enc,
+				types:          types,
+				statusCode:     http.StatusOK,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressResponseWriter buffers the first write to decide whether a
+// response is worth compressing, and only switches a pooled encoder in
+// (and sets Content-Encoding) once it commits to doing so.
+type compressResponseWriter struct {
+	http.ResponseWriter
+
+	level    int
+	encoding string
+	types    []string
+
+	statusCode int
+	headerSent bool
+
+	decided  bool
+	compress bool
+	writer   compressWriter
+	buf      []byte
+}
+
+func (w *compressResponseWriter) WriteHeader(status int) {
+	w.statusCode = status
+}
+
+func (w *compressResponseWriter) sendHeader() {
+	if w.headerSent {
+		return
+	}
+	w.headerSent = true
+	w.ResponseWriter.WriteHeader(w.statusCode)
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if w.compress {
+		return w.writer.Write(b)
+	}
+	if w.decided {
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+
+	if !w.contentTypeAllowed() || w.alreadyEncoded() {
+		w.decided = true
+		return w.flushBuffered()
+	}
+
+	if len(w.buf) < minCompressSize {
+		// Not committed either way yet; hang onto it until Write sees
+		// more or Close flushes it uncompressed.
+		return len(b), nil
+	}
+
+	enc, err := getEncoder(w.encoding, w.level)
+	if err != nil {
+		w.decided = true
+		return w.flushBuffered()
+	}
+
+	w.decided = true
+	w.compress = true
+	w.writer = enc
+	w.writer.Reset(w.ResponseWriter)
+	w.ResponseWriter.Header().Set("Content-Encoding", w.encoding)
+	w.ResponseWriter.Header().Del("Content-Length")
+	w.sendHeader()
+
+	if _, err := w.writer.Write(w.buf); err != nil {
+		return 0, err
+	}
+	w.buf = nil
+	return len(b), nil
+}
+
+func (w *compressResponseWriter) flushBuffered() (int, error) {
+	w.sendHeader()
+	n, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return n, err
+}
+
+func (w *compressResponseWriter) contentTypeAllowed() bool {
+	ct := w.ResponseWriter.Header().Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	ct = strings.ToLower(strings.TrimSpace(ct))
+
+	for _, t := range w.types {
+		if strings.HasSuffix(t, "/") {
+			if strings.HasPrefix(ct, t) {
+				return true
+			}
+		} else if ct == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressResponseWriter) alreadyEncoded() bool {
+	return w.ResponseWriter.Header().Get("Content-Encoding") != ""
+}
+
+// Close flushes any response still sitting in the buffer (uncompressed,
+// since we never decided to commit to an encoder), sends the status
+// line if nothing has yet, and returns the encoder to its pool.
+func (w *compressResponseWriter) Close() error {
+	if len(w.buf) > 0 {
+		if _, err := w.flushBuffered(); err != nil {
+			return err
+		}
+	}
+	w.sendHeader()
+
+	if w.writer != nil {
+		err := w.writer.Close()
+		putEncoder(w.encoding, w.level, w.writer)
+		w.writer = nil
+		return err
+	}
+	return nil
+}
+
+// Flush implements http.Flusher so streaming responses (SSE) still
+// work. A response that's still undecided (buffered below
+// minCompressSize, waiting to see if more is coming) has to be forced
+// to a decision first, or the bytes sitting in w.buf never reach the
+// client and the underlying Flusher's implicit header write collides
+// with our own later sendHeader call.
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		w.decided = true
+		w.flushBuffered()
+	}
+	if w.writer != nil {
+		w.writer.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker so WebSocket upgrades still work.
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("router: underlying ResponseWriter does not support Hijack")
+	}
+	return hj.Hijack()
+}
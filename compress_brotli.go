@@ -0,0 +1,18 @@
+//go:build brotli
+
+package router
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Brotli support is opt-in via the "brotli" build tag, since it pulls
+// in github.com/andybalholm/brotli; without the tag, Compress only
+// offers gzip and deflate (see compress_nobrotli.go).
+func init() {
+	registerEncoding(encBrotli, func(level int) (compressWriter, error) {
+		return brotli.NewWriterLevel(io.Discard, level), nil
+	})
+}
@@ -0,0 +1,7 @@
+//go:build !brotli
+
+package router
+
+// Brotli support is opt-in via the "brotli" build tag, since it pulls
+// in github.com/andybalholm/brotli; without the tag, Compress only
+// offers gzip and deflate.
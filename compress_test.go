@@ -0,0 +1,92 @@
+package router
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newCompressWriter(rec *httptest.ResponseRecorder) *compressResponseWriter {
+	return &compressResponseWriter{
+		ResponseWriter: rec,
+		level:          gzip.DefaultCompression,
+		encoding:       encGzip,
+		types:          defaultCompressibleTypes,
+		statusCode:     http.StatusOK,
+	}
+}
+
+func TestCompressResponseWriterPassesThroughShortBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newCompressWriter(rec)
+
+	if _, err := w.Write([]byte("short")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("Content-Encoding = %q, want unset for a body under minCompressSize", got)
+	}
+	if rec.Body.String() != "short" {
+		t.Fatalf("body = %q, want %q", rec.Body.String(), "short")
+	}
+}
+
+func TestCompressResponseWriterCompressesLargeBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newCompressWriter(rec)
+
+	body := strings.Repeat("a", minCompressSize+1)
+	if _, err := w.Write([]byte(body)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := rec.Header().Get("Content-Encoding"); got != encGzip {
+		t.Fatalf("Content-Encoding = %q, want %q", got, encGzip)
+	}
+	gr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	defer gr.Close()
+	decoded, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("reading decompressed body: %v", err)
+	}
+	if string(decoded) != body {
+		t.Fatalf("decompressed body doesn't round-trip")
+	}
+}
+
+// TestCompressResponseWriterFlushDeliversBufferedBytes covers the SSE
+// regression: a write that's still under minCompressSize (so Write
+// hasn't committed to compress or passthrough yet) must reach the
+// client as soon as Flush is called, not sit in w.buf until Close.
+func TestCompressResponseWriterFlushDeliversBufferedBytes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newCompressWriter(rec)
+
+	if _, err := w.Write([]byte("event: ping\n\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	w.Flush()
+
+	if rec.Body.String() != "event: ping\n\n" {
+		t.Fatalf("body after Flush = %q, want the write to have been delivered", rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Fatalf("underlying ResponseWriter's Flush was never called")
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("Content-Encoding = %q, want unset (buffered write stayed under the compress threshold)", got)
+	}
+}
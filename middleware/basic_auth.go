@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/alphastyle/router"
+)
+
+// BasicAuth challenges with HTTP Basic auth in realm, letting the
+// request through only when validator accepts the supplied username
+// and password.
+func BasicAuth(realm string, validator func(user, pass string) bool) func(func(*router.Context)) func(*router.Context) {
+	return func(next func(*router.Context)) func(*router.Context) {
+		return func(c *router.Context) {
+			user, pass, ok := c.Request.BasicAuth()
+			if !ok || !validator(user, pass) {
+				c.ResponseWriter.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
+				http.Error(c.ResponseWriter, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next(c)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alphastyle/logger"
+	"github.com/alphastyle/router"
+)
+
+// statusWriter wraps a ResponseWriter so Logger can see the status
+// code and byte count after the handler runs.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Logger logs one structured access line per request: method, path,
+// status, response size, duration, and the request ID if RequestID ran
+// earlier in the chain.
+func Logger(next func(*router.Context)) func(*router.Context) {
+	return func(c *router.Context) {
+		sw := &statusWriter{ResponseWriter: c.ResponseWriter}
+		c.ResponseWriter = sw
+
+		start := time.Now()
+		next(c)
+		duration := time.Since(start)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		logger.Info(fmt.Sprintf(
+			"%s %s %d %dB %s request_id=%s",
+			c.Request.Method, c.Request.URL.Path, sw.status, sw.size, duration, RequestIDFromContext(c),
+		))
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/alphastyle/router"
+)
+
+// RealIP rewrites the request's RemoteAddr from X-Forwarded-For or
+// X-Real-IP, but only when the current RemoteAddr falls inside one of
+// trustedProxies — otherwise any client could spoof its own address by
+// setting those headers directly.
+func RealIP(trustedProxies ...string) func(func(*router.Context)) func(*router.Context) {
+	nets := parseCIDRs(trustedProxies)
+
+	return func(next func(*router.Context)) func(*router.Context) {
+		return func(c *router.Context) {
+			if isTrustedProxy(c.Request.RemoteAddr, nets) {
+				if ip := forwardedIP(c.Request.Header); ip != "" {
+					c.Request.RemoteAddr = ip
+				}
+			}
+			next(c)
+		}
+	}
+}
+
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if _, n, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+func isTrustedProxy(remoteAddr string, nets []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func forwardedIP(h http.Header) string {
+	if fwd := h.Get("X-Forwarded-For"); fwd != "" {
+		return strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	if ip := h.Get("X-Real-IP"); ip != "" {
+		return strings.TrimSpace(ip)
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/alphastyle/logger"
+	"github.com/alphastyle/router"
+)
+
+// Recoverer catches panics from the rest of the middleware chain and
+// the handler, logs the stack via the repo's logger, and writes a 500
+// instead of letting the panic escape to net/http (which just closes
+// the connection). Register it first so it wraps everything after it.
+func Recoverer(next func(*router.Context)) func(*router.Context) {
+	return func(c *router.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				logger.Error(fmt.Errorf("%v", rec), "panic recovered:\n"+string(debug.Stack()))
+				http.Error(c.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next(c)
+	}
+}
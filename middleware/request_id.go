@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/alphastyle/router"
+	uuid "github.com/satori/go.uuid"
+)
+
+// RequestIDHeader is the header RequestID reads an inbound ID from and
+// echoes it back on.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID reads X-Request-ID off the incoming request, or mints a new
+// one, stashes it on the request context, and echoes it back on the
+// response so callers can correlate logs across a request's hops.
+func RequestID(next func(*router.Context)) func(*router.Context) {
+	return func(c *router.Context) {
+		id := c.Request.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = uuid.NewV4().String()
+		}
+
+		c.ResponseWriter.Header().Set(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+		next(c)
+	}
+}
+
+// RequestIDFromContext returns the request ID stashed by RequestID, or
+// "" if RequestID hasn't run for this request.
+func RequestIDFromContext(c *router.Context) string {
+	id, _ := c.Request.Context().Value(requestIDKey{}).(string)
+	return id
+}
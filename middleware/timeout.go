@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alphastyle/router"
+)
+
+// timeoutWriter serializes status/body writes behind a mutex so a
+// still-running handler goroutine and the timeout path can't race on
+// the same underlying ResponseWriter; once timedOut is set, further
+// writes from the handler are dropped (WriteHeader does nothing and
+// Write returns http.ErrHandlerTimeout). Header() hands the
+// handler a private header map rather than the real ResponseWriter's,
+// mirroring stdlib's http.TimeoutHandler: the real Header() map is
+// only ever touched under tw.mu, by whichever side (handler or
+// timeout path) commits first.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	header      http.Header
+	timedOut    bool
+	wroteHeader bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		// The real headers already belong to the timeout response;
+		// hand back a throwaway map so a still-running handler can't
+		// reach them.
+		return make(http.Header)
+	}
+	if tw.header == nil {
+		tw.header = make(http.Header)
+	}
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.commitHeaderLocked()
+	tw.w.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.commitHeaderLocked()
+		tw.w.WriteHeader(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+// commitHeaderLocked copies the handler's private header map onto the
+// real ResponseWriter and marks the header as sent. Callers must hold
+// tw.mu and have already checked timedOut/wroteHeader.
+func (tw *timeoutWriter) commitHeaderLocked() {
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+}
+
+// Timeout wraps the handler with a context canceled after d; if the
+// handler hasn't written a response by then, Timeout writes a 503
+// itself. Well-behaved handlers should watch c.Request.Context().Done()
+// for long operations, since Go has no way to forcibly abort a goroutine.
+func Timeout(d time.Duration) func(func(*router.Context)) func(*router.Context) {
+	return func(next func(*router.Context)) func(*router.Context) {
+		return func(c *router.Context) {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{w: c.ResponseWriter}
+			c.ResponseWriter = tw
+			c.Request = c.Request.WithContext(ctx)
+
+			done := make(chan struct{})
+			go func() {
+				next(c)
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				if !tw.wroteHeader {
+					http.Error(tw.w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				}
+				tw.mu.Unlock()
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alphastyle/router"
+)
+
+// TestTimeoutWriterHeaderRaceSafe covers the race the review flagged:
+// a handler that keeps running past the deadline and touches
+// c.ResponseWriter.Header() must not race with the timeout path's own
+// http.Error call on the same underlying ResponseWriter. Run with
+// `go test -race` to catch a regression.
+func TestTimeoutWriterHeaderRaceSafe(t *testing.T) {
+	mw := Timeout(10 * time.Millisecond)
+
+	handler := mw(func(c *router.Context) {
+		for i := 0; i < 50; i++ {
+			c.ResponseWriter.Header().Set("X-Probe", "1")
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	c := &router.Context{
+		ResponseWriter: rec,
+		Request:        httptest.NewRequest(http.MethodGet, "/", nil),
+	}
+
+	handler(c)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	// Give the (intentionally abandoned) handler goroutine time to
+	// finish its loop so -race has a chance to see any overlap.
+	time.Sleep(75 * time.Millisecond)
+}
+
+func TestTimeoutWriterLetsFastHandlerThrough(t *testing.T) {
+	mw := Timeout(50 * time.Millisecond)
+
+	handler := mw(func(c *router.Context) {
+		c.ResponseWriter.WriteHeader(http.StatusCreated)
+	})
+
+	rec := httptest.NewRecorder()
+	c := &router.Context{
+		ResponseWriter: rec,
+		Request:        httptest.NewRequest(http.MethodGet, "/", nil),
+	}
+
+	handler(c)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+}
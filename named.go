@@ -0,0 +1,173 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// namedRegistry maps a route's name to its full pattern (group prefix
+// included), scoped to a single mux so independent router.New() trees
+// (e.g. in tests, or multiple app instances in one process) don't
+// clobber each other's route names.
+type namedRegistry struct {
+	mu     sync.RWMutex
+	routes map[string]string
+}
+
+func newNamedRegistry() *namedRegistry {
+	return &namedRegistry{routes: map[string]string{}}
+}
+
+func (nr *namedRegistry) set(name, pattern string) {
+	nr.mu.Lock()
+	nr.routes[name] = pattern
+	nr.mu.Unlock()
+}
+
+func (nr *namedRegistry) get(name string) (string, bool) {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+	pattern, ok := nr.routes[name]
+	return pattern, ok
+}
+
+// GETNamed registers h for GET requests on pattern and records it
+// under name in the named route registry.
+func (g *Group) GETNamed(name, pattern string, h handlerFunc) {
+	g.HandleNamed(name, "GET", pattern, h)
+}
+
+// POSTNamed registers h for POST requests on pattern and records it
+// under name in the named route registry.
+func (g *Group) POSTNamed(name, pattern string, h handlerFunc) {
+	g.HandleNamed(name, "POST", pattern, h)
+}
+
+// HandleNamed is Handle plus recording the route's full pattern under
+// name so URL/MustURL can look it up later.
+func (g *Group) HandleNamed(name, method, pattern string, h handlerFunc) {
+	g.Handle(method, pattern, h)
+	g.namedRoutes.set(name, g.prefix+pattern)
+}
+
+// URL reconstructs the URL registered under name, substituting params
+// into the pattern's :name/{name}/*rest segments. params can be given
+// as alternating key, value pairs (e.g. "id", 42), naming which
+// segment each value belongs to, or as one positional value per
+// segment, in the order the pattern declares them. It returns an
+// error, rather than panicking, when the supplied params don't match
+// what the pattern expects.
+func (g *Group) URL(name string, params ...interface{}) (string, error) {
+	return buildURL(g.namedRoutes, name, params...)
+}
+
+// MustURL is URL, panicking instead of returning an error; handy when
+// calling from a template, where there's no good way to propagate one.
+func (g *Group) MustURL(name string, params ...interface{}) string {
+	return mustBuildURL(g.namedRoutes, name, params...)
+}
+
+// URL is the Context-side equivalent of Group.URL, so handlers can
+// build links without holding onto their Group.
+func (c *Context) URL(name string, params ...interface{}) (string, error) {
+	return buildURL(c.routes, name, params...)
+}
+
+// MustURL is the Context-side equivalent of Group.MustURL.
+func (c *Context) MustURL(name string, params ...interface{}) string {
+	return mustBuildURL(c.routes, name, params...)
+}
+
+func mustBuildURL(reg *namedRegistry, name string, params ...interface{}) string {
+	u, err := buildURL(reg, name, params...)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func buildURL(reg *namedRegistry, name string, params ...interface{}) (string, error) {
+	pattern, ok := reg.get(name)
+	if !ok {
+		return "", fmt.Errorf("router: no route named %q", name)
+	}
+
+	segments := splitPath(pattern)
+	captures := captureNames(segments)
+
+	values, ok := keyedParams(captures, params)
+	if !ok {
+		values, ok = positionalParams(captures, params)
+	}
+	if !ok {
+		return "", fmt.Errorf("router: URL(%q): params don't match pattern %q, expected key, value pairs or %d positional value(s)", name, pattern, len(captures))
+	}
+
+	built := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		segName, kind := segmentKind(seg)
+		if kind == segStatic {
+			built = append(built, seg)
+			continue
+		}
+		built = append(built, values[segName])
+	}
+
+	return "/" + strings.Join(built, "/"), nil
+}
+
+// captureNames returns the ordered :name/{name}/*rest segment names in
+// a split pattern.
+func captureNames(segments []string) []string {
+	var names []string
+	for _, seg := range segments {
+		name, kind := segmentKind(seg)
+		if kind != segStatic {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// keyedParams reads params as alternating (key, value) pairs, and
+// succeeds only when every capture in the pattern got a value and
+// every key names an actual capture.
+func keyedParams(captures []string, params []interface{}) (map[string]string, bool) {
+	if len(params)%2 != 0 {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return nil, false
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	if len(values) != len(captures) {
+		return nil, false
+	}
+	for _, c := range captures {
+		if _, ok := values[c]; !ok {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// positionalParams matches params to captures strictly by order, one
+// value per capture, for callers that don't want to name the keys.
+func positionalParams(captures []string, params []interface{}) (map[string]string, bool) {
+	if len(params) != len(captures) {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(captures))
+	for i, c := range captures {
+		values[c] = fmt.Sprint(params[i])
+	}
+	return values, true
+}
@@ -0,0 +1,85 @@
+package router
+
+import "testing"
+
+func TestURLKeyedParams(t *testing.T) {
+	g := New()
+	g.GETNamed("user.show", "/users/:id", func(*Context) {})
+
+	u, err := g.URL("user.show", "id", 42)
+	if err != nil {
+		t.Fatalf("URL: %v", err)
+	}
+	if u != "/users/42" {
+		t.Fatalf("URL = %q, want %q", u, "/users/42")
+	}
+}
+
+func TestURLPositionalParams(t *testing.T) {
+	g := New()
+	g.GETNamed("user.show", "/users/:id", func(*Context) {})
+
+	u, err := g.URL("user.show", 42)
+	if err != nil {
+		t.Fatalf("URL: %v", err)
+	}
+	if u != "/users/42" {
+		t.Fatalf("URL = %q, want %q", u, "/users/42")
+	}
+}
+
+func TestURLPositionalParamsMultipleCaptures(t *testing.T) {
+	g := New()
+	g.GETNamed("post.comment", "/posts/:postID/comments/:commentID", func(*Context) {})
+
+	u, err := g.URL("post.comment", 7, 99)
+	if err != nil {
+		t.Fatalf("URL: %v", err)
+	}
+	if u != "/posts/7/comments/99" {
+		t.Fatalf("URL = %q, want %q", u, "/posts/7/comments/99")
+	}
+}
+
+func TestURLMissingParam(t *testing.T) {
+	g := New()
+	g.GETNamed("user.show", "/users/:id", func(*Context) {})
+
+	if _, err := g.URL("user.show"); err == nil {
+		t.Fatalf("URL with no params = nil error, want an error")
+	}
+}
+
+func TestURLUnknownName(t *testing.T) {
+	g := New()
+
+	if _, err := g.URL("nope"); err == nil {
+		t.Fatalf("URL for an unregistered name = nil error, want an error")
+	}
+}
+
+func TestURLKeyedParamNotASegment(t *testing.T) {
+	g := New()
+	g.GETNamed("user.show", "/users/:id", func(*Context) {})
+
+	if _, err := g.URL("user.show", "name", "bob"); err == nil {
+		t.Fatalf("URL with a key that isn't a segment name = nil error, want an error")
+	}
+}
+
+// TestURLRegistryIsolatedPerMux covers the process-wide-global
+// regression: two independent router.New() trees must not see each
+// other's named routes.
+func TestURLRegistryIsolatedPerMux(t *testing.T) {
+	a := New()
+	a.GETNamed("home", "/home", func(*Context) {})
+
+	b := New()
+
+	if _, err := b.URL("home"); err == nil {
+		t.Fatalf("b.URL(\"home\") resolved a route registered on a different mux, want an error")
+	}
+	if u, err := a.URL("home"); err != nil || u != "/home" {
+		t.Fatalf("a.URL(\"home\") = %q, %v; want \"/home\", nil", u, err)
+	}
+}
@@ -5,25 +5,44 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"io"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/alphastyle/logger"
-	uuid "github.com/satori/go.uuid"
 )
 
 // mux is the multiplexer struct
 type mux struct {
 	*http.ServeMux
-	middle []handlerFunc
+	middle       []wrapperFunc
+	root         *node
+	sessionStore SessionStore
+	namedRoutes  *namedRegistry
+}
+
+// ServeHTTP resolves the request against the path-parameter trie first,
+// preferring static over named over wildcard matches, and falls back to
+// the embedded ServeMux (used by ServeFiles) when nothing matches.
+func (m *mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, params, matched, allowed := m.root.lookup(r.Method, r.URL.Path)
+	if handler != nil {
+		handler.ServeHTTP(w, withParams(r, params))
+		return
+	}
+
+	if matched {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	m.ServeMux.ServeHTTP(w, r)
 }
 
 // Group is to divide request middleware
 type Group struct {
 	*mux
-	middleware []handlerFunc
+	middleware []wrapperFunc
 	prefix     string
 }
 
@@ -31,27 +50,27 @@ type Group struct {
 type Context struct {
 	http.ResponseWriter
 	*http.Request
+
+	store    SessionStore
+	sessions map[string]*Session
+	routes   *namedRegistry
 }
 
 // handlerFunc is custom http.HandleFunc type
 type handlerFunc func(*Context)
 
-// Gzip Compression
-type gzipResponseWriter struct {
-	io.Writer
-	http.ResponseWriter
-}
-
-// Gzip Write
-func (w gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
-}
+// wrapperFunc wraps the next handler in the chain, so middleware can
+// run code before and after it, short-circuit by never calling next,
+// or defer work with recover/defer around the call.
+type wrapperFunc func(next handlerFunc) handlerFunc
 
 // New will create a new group
 func New() *Group {
 	return &Group{
 		mux: &mux{
-			ServeMux: http.NewServeMux(),
+			ServeMux:    http.NewServeMux(),
+			root:        newNode(),
+			namedRoutes: newNamedRegistry(),
 		},
 	}
 }
@@ -60,63 +79,163 @@ func (h handlerFunc) ServeHTTP(c *Context) {
 	h(c)
 }
 
-// GET is a custom http.HandlerFunc that only allow GET requests
+// GET registers h for GET requests on pattern
 func (g *Group) GET(pattern string, h handlerFunc) {
-	handler := g.handleRequest(h, "GET")
-	g.Handle(g.prefix+pattern, http.HandlerFunc(handler))
+	g.Handle("GET", pattern, h)
 }
 
-// POST is a custom http.HandlerFunc that only allow POST requests
+// POST registers h for POST requests on pattern
 func (g *Group) POST(pattern string, h handlerFunc) {
-	handler := g.handleRequest(h, "POST")
-	g.Handle(g.prefix+pattern, http.HandlerFunc(handler))
+	g.Handle("POST", pattern, h)
+}
+
+// PUT registers h for PUT requests on pattern
+func (g *Group) PUT(pattern string, h handlerFunc) {
+	g.Handle("PUT", pattern, h)
+}
+
+// DELETE registers h for DELETE requests on pattern
+func (g *Group) DELETE(pattern string, h handlerFunc) {
+	g.Handle("DELETE", pattern, h)
+}
+
+// PATCH registers h for PATCH requests on pattern
+func (g *Group) PATCH(pattern string, h handlerFunc) {
+	g.Handle("PATCH", pattern, h)
+}
+
+// HEAD registers h for HEAD requests on pattern
+func (g *Group) HEAD(pattern string, h handlerFunc) {
+	g.Handle("HEAD", pattern, h)
+}
+
+// OPTIONS registers h for OPTIONS requests on pattern
+func (g *Group) OPTIONS(pattern string, h handlerFunc) {
+	g.Handle("OPTIONS", pattern, h)
+}
+
+// Handle registers h for method on pattern, prefixed with the group's
+// own prefix, behind the group's middleware chain.
+func (g *Group) Handle(method, pattern string, h handlerFunc) {
+	g.root.insert(method, g.prefix+pattern, g.handleRequest(h))
 }
 
-// handleRequest will check the request method and handle middleware
-func (g *Group) handleRequest(h handlerFunc, method string) http.HandlerFunc {
+// handleRequest wraps h so it runs behind the group's middleware chain.
+// The method itself is no longer checked here; the trie only ever calls
+// this handler for the method it was registered under.
+func (g *Group) handleRequest(h handlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == method {
-			// w.Header().Set("Content-Type", "text/HTML")
-			mrw := &Context{w, r}
-			g.handleMiddleware(mrw)
-			h.ServeHTTP(mrw)
-		} else {
-			http.NotFound(w, r)
+		mrw := &Context{ResponseWriter: w, Request: r, store: g.mux.sessionStore, routes: g.mux.namedRoutes}
+		if mrw.store != nil {
+			mrw.ResponseWriter = &sessionWriter{ResponseWriter: w, c: mrw}
 		}
+		g.chain(h)(mrw)
 	}
 }
 
-// handleMiddleware will serve the correct middleware for the request
-func (g *Group) handleMiddleware(c *Context) {
-	// Global Middleware
-	for _, v := range g.middle {
-		v.ServeHTTP(c)
+// chain wraps h with the group's own middleware and then the global
+// middleware, so global middleware always runs outermost and can still
+// short-circuit or observe the response of everything inside it. It's
+// built fresh per request so middleware added via Use after routes are
+// registered still applies.
+func (g *Group) chain(h handlerFunc) handlerFunc {
+	chained := h
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		chained = g.middleware[i](chained)
 	}
-
-	// Group Middleware
-	for _, v := range g.middleware {
-		v.ServeHTTP(c)
+	for i := len(g.middle) - 1; i >= 0; i-- {
+		chained = g.middle[i](chained)
 	}
+	return chained
 }
 
-// Use is to make custom global middleware
-// or group middleware
-func (g *Group) Use(h ...handlerFunc) {
-	if g.prefix == "" {
-		// Global Middleware
-		for _, v := range h {
-			g.middle = append(g.middle, v)
+// Use registers global middleware (on the root group) or group
+// middleware (on a prefixed group). Each argument is either a
+// handlerFunc (or plain func(*Context)), run before the handler with no
+// control over it, or a func(handlerFunc) handlerFunc (or
+// func(func(*Context)) func(*Context)), which wraps the next handler and
+// can observe the response, short-circuit, or defer work around it.
+func (g *Group) Use(mw ...interface{}) {
+	for _, m := range mw {
+		wrapped, err := toWrapper(m)
+		if err != nil {
+			logger.Error(err, "Use error")
+			continue
 		}
-	} else {
-		// Group Middleware
-		for _, v := range h {
-			g.middleware = append(g.middleware, v)
+
+		if g.prefix == "" {
+			// Global Middleware
+			g.middle = append(g.middle, wrapped)
+		} else {
+			// Group Middleware
+			g.middleware = append(g.middleware, wrapped)
 		}
 	}
 }
 
+// toWrapper normalizes the two middleware shapes Use accepts into a
+// single wrapperFunc.
+func toWrapper(m interface{}) (wrapperFunc, error) {
+	switch v := m.(type) {
+	case wrapperFunc:
+		return v, nil
+	case func(handlerFunc) handlerFunc:
+		return wrapperFunc(v), nil
+	case func(func(*Context)) func(*Context):
+		// The shape exported middleware packages use, since they can't
+		// name the unexported handlerFunc type themselves.
+		return func(next handlerFunc) handlerFunc {
+			inner := v(func(c *Context) { next(c) })
+			return func(c *Context) { inner(c) }
+		}, nil
+	case handlerFunc:
+		return func(next handlerFunc) handlerFunc {
+			return func(c *Context) {
+				v.ServeHTTP(c)
+				next(c)
+			}
+		}, nil
+	case func(*Context):
+		return toWrapper(handlerFunc(v))
+	default:
+		return nil, errors.New("router: Use expects a handlerFunc, func(handlerFunc) handlerFunc, or func(func(*Context)) func(*Context)")
+	}
+}
+
+// With returns a shallow copy of g with extra middleware appended,
+// sharing the same mux and prefix (chi-style), so callers can scope
+// middleware like auth to a subset of routes without a new URL group.
+// Unlike Use, the middleware is always scoped to the returned group,
+// even when g is the root group (prefix == ""): going through Use
+// there would push it onto the shared mux as global middleware,
+// applying it to every route in the app instead of just g's.
+func (g *Group) With(mw ...interface{}) *Group {
+	newGroup := &Group{
+		mux:        g.mux,
+		prefix:     g.prefix,
+		middleware: append([]wrapperFunc{}, g.middleware...),
+	}
+	for _, m := range mw {
+		wrapped, err := toWrapper(m)
+		if err != nil {
+			logger.Error(err, "With error")
+			continue
+		}
+		newGroup.middleware = append(newGroup.middleware, wrapped)
+	}
+	return newGroup
+}
+
+// UseSessionStore wires a SessionStore into the group tree so
+// Context.Session, NewSession, and DeleteSession read and write
+// through it. It applies to the whole group tree, not just g, since
+// the store lives on the shared mux.
+func (g *Group) UseSessionStore(store SessionStore) {
+	g.mux.sessionStore = store
+}
+
 // Group makes it possible to have custom group middleware
-func (g *Group) Group(pattern string, h ...handlerFunc) *Group {
+func (g *Group) Group(pattern string, h ...interface{}) *Group {
 	// Initialize new group
 	newGroup := &Group{
 		mux: g.mux,
@@ -124,11 +243,7 @@ func (g *Group) Group(pattern string, h ...handlerFunc) *Group {
 
 	if pattern != "" && strings.HasPrefix(pattern, "/") {
 		newGroup.prefix = pattern
-		// Appending middleware to the new group
-		for _, v := range h {
-			newGroup.middleware = append(newGroup.middleware, v)
-		}
-
+		newGroup.Use(h...)
 	} else {
 		err := errors.New("Url pattern can't be empty and has to start with / (slash)!")
 		logger.Error(err, "Group error")
@@ -137,29 +252,10 @@ func (g *Group) Group(pattern string, h ...handlerFunc) *Group {
 	return newGroup
 }
 
-// Gzip compress all served files
-func Gzip(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow the browser to cache content for 1 day (less traffic)
-		w.Header().Set("Cache-Control", "max-age:86400")
-
-		// if request does not accept Gzip then return without Gzip
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			handler.ServeHTTP(w, r)
-		}
-
-		// Allow gzip
-		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		gzw := gzipResponseWriter{Writer: gz, ResponseWriter: w}
-		handler.ServeHTTP(gzw, r)
-	})
-}
-
 // ServeFiles serve static files
 func (g *Group) ServeFiles(urlPath string, dirPath string, prefix string) {
-	g.Handle(urlPath, Gzip(http.StripPrefix(prefix, http.FileServer(http.Dir(dirPath)))))
+	handler := Compress(gzip.DefaultCompression)(http.StripPrefix(prefix, http.FileServer(http.Dir(dirPath))))
+	g.mux.ServeMux.Handle(urlPath, handler)
 }
 
 // ServeFavicon will serve the favicon you choose
@@ -196,76 +292,3 @@ func (c *Context) GetContext(key string) interface{} {
 	val := c.Context().Value(key)
 	return val
 }
-
-// Got this from Stackoverflow (Copy / Paste)
-// Will create a random string with the length n
-// func randomValue(n int, src rand.Source) string {
-// 	letterBytes := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-// 	letterIdxBits := uint(6)              // 6 bits to represent a letter index
-// 	letterIdxMask := 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
-// 	letterIdxMax := 63 / letterIdxBits    // # of letter indices fitting in 63 bits
-
-// 	b := make([]byte, n)
-// 	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
-// 	for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
-// 		if remain == 0 {
-// 			cache, remain = src.Int63(), letterIdxMax
-// 		}
-// 		if idx := int(cache & int64(letterIdxMask)); idx < len(letterBytes) {
-// 			b[i] = letterBytes[idx]
-// 			i--
-// 		}
-// 		cache >>= letterIdxBits
-// 		remain--
-// 	}
-
-// 	return string(b)
-// }
-
-func randomValue() uuid.UUID {
-	return uuid.NewV4()
-}
-
-// NewSession will create a new cookie session
-func (c *Context) NewSession(name string) {
-	// value := randomValue(40, rand.NewSource(time.Now().UnixNano()))
-	value := randomValue()
-	expiration := time.Now().Add(30 * time.Minute) // TODO make time a config setting
-
-	cookie := &http.Cookie{
-		Name:    name,
-		Value:   value.String(),
-		Expires: expiration,
-		Path:    "/",
-	}
-
-	http.SetCookie(c.ResponseWriter, cookie)
-}
-
-// DeleteSession will delete the cookie session
-func (c *Context) DeleteSession(name string) {
-	cookie := &http.Cookie{
-		Name:    name,
-		Value:   "deleted",
-		Expires: time.Now(),
-		MaxAge:  -1,
-		Path:    "/",
-	}
-
-	http.SetCookie(c.ResponseWriter, cookie)
-}
-
-// GetSession will get the cookie session
-func (c *Context) GetSession(name string) (*http.Cookie, error) {
-	cookie, err := c.Cookie(name)
-	return cookie, err
-}
-
-// Listen will start the server (http.ListenAndServe)
-func (g *Group) Listen(serve string) error {
-	// listening @ :PORT
-	logger.Info("listening @" + serve)
-
-	// start listening
-	return http.ListenAndServe(serve, g)
-}
@@ -0,0 +1,147 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// orderingMiddleware appends label to log before calling next, so
+// tests can assert on middleware/handler execution order.
+func orderingMiddleware(log *[]string, label string) func(handlerFunc) handlerFunc {
+	return func(next handlerFunc) handlerFunc {
+		return func(c *Context) {
+			*log = append(*log, label)
+			next(c)
+		}
+	}
+}
+
+func TestUseOrdersGlobalBeforeGroupMiddleware(t *testing.T) {
+	var log []string
+
+	root := New()
+	root.Use(orderingMiddleware(&log, "global"))
+
+	api := root.Group("/api")
+	api.Use(orderingMiddleware(&log, "group"))
+	api.GET("/ping", func(c *Context) {
+		log = append(log, "handler")
+	})
+
+	rec := httptest.NewRecorder()
+	root.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ping", nil))
+
+	want := []string{"global", "group", "handler"}
+	if len(log) != len(want) {
+		t.Fatalf("call order = %v, want %v", log, want)
+	}
+	for i, label := range want {
+		if log[i] != label {
+			t.Fatalf("call order = %v, want %v", log, want)
+		}
+	}
+}
+
+// TestToWrapperAcceptsAllMiddlewareShapes covers the three shapes Use
+// (and therefore With) accept: the package's own func(handlerFunc)
+// handlerFunc, a bare handlerFunc run for side effects before next,
+// and the func(func(*Context)) func(*Context) shape cross-package
+// middleware (e.g. router/middleware) has to use since it can't name
+// the unexported handlerFunc type.
+func TestToWrapperAcceptsAllMiddlewareShapes(t *testing.T) {
+	var log []string
+
+	root := New()
+	root.Use(func(next handlerFunc) handlerFunc {
+		return func(c *Context) {
+			log = append(log, "wrapperFunc-shape")
+			next(c)
+		}
+	})
+	root.Use(handlerFunc(func(c *Context) {
+		log = append(log, "handlerFunc-shape")
+	}))
+	root.Use(func(next func(*Context)) func(*Context) {
+		return func(c *Context) {
+			log = append(log, "cross-package-shape")
+			next(c)
+		}
+	})
+	root.GET("/ping", func(c *Context) {
+		log = append(log, "handler")
+	})
+
+	rec := httptest.NewRecorder()
+	root.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	want := []string{"wrapperFunc-shape", "handlerFunc-shape", "cross-package-shape", "handler"}
+	if len(log) != len(want) {
+		t.Fatalf("call order = %v, want %v", log, want)
+	}
+	for i, label := range want {
+		if log[i] != label {
+			t.Fatalf("call order = %v, want %v", log, want)
+		}
+	}
+}
+
+// TestWithScopesMiddlewareToItsOwnGroup is a regression test for a
+// bug where With, when called on the root group (prefix == ""),
+// routed its middleware through Use's global/group branching and
+// ended up pushing it onto the shared mux as global middleware,
+// applying it to every route in the app instead of just the routes
+// registered on the group With returned.
+func TestWithScopesMiddlewareToItsOwnGroup(t *testing.T) {
+	var log []string
+
+	root := New()
+	scoped := root.With(orderingMiddleware(&log, "scoped"))
+	scoped.GET("/scoped", func(c *Context) {
+		log = append(log, "scoped-handler")
+	})
+	root.GET("/unscoped", func(c *Context) {
+		log = append(log, "unscoped-handler")
+	})
+
+	rec := httptest.NewRecorder()
+	root.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unscoped", nil))
+	if got, want := log, []string{"unscoped-handler"}; len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("/unscoped call log = %v, want %v (With's middleware leaked onto an unrelated route)", got, want)
+	}
+
+	log = nil
+	rec = httptest.NewRecorder()
+	root.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/scoped", nil))
+	want := []string{"scoped", "scoped-handler"}
+	if len(log) != len(want) || log[0] != want[0] || log[1] != want[1] {
+		t.Fatalf("/scoped call log = %v, want %v", log, want)
+	}
+}
+
+func TestServeHTTPSetsAllowHeaderOn405(t *testing.T) {
+	root := New()
+	root.GET("/users", func(c *Context) {})
+
+	rec := httptest.NewRecorder()
+	root.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET" {
+		t.Fatalf("Allow header = %q, want %q", got, "GET")
+	}
+}
+
+func TestServeHTTPNotFoundForUnknownPath(t *testing.T) {
+	root := New()
+	root.GET("/users", func(c *Context) {})
+
+	rec := httptest.NewRecorder()
+	root.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
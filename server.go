@@ -0,0 +1,201 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alphastyle/logger"
+)
+
+// ShutdownTimeout bounds how long Listen waits for in-flight requests
+// to finish after SIGINT/SIGTERM before giving up and returning.
+var ShutdownTimeout = 10 * time.Second
+
+// listenFDEnv tells a hot-restarted child that fd 3 (the first, and
+// only, entry in ExtraFiles) is already a listening socket handed down
+// by its parent.
+const listenFDEnv = "ROUTER_LISTEN_FD"
+
+// Listen starts the server on addr and blocks until SIGINT or SIGTERM,
+// then drains in-flight requests for up to ShutdownTimeout before
+// returning. SIGUSR2 instead triggers a zero-downtime hot restart: the
+// process re-execs itself, handing its listening socket to the child
+// over ExtraFiles, and returns once its own in-flight connections finish.
+func (g *Group) Listen(addr string) error {
+	return g.ListenContext(context.Background(), addr)
+}
+
+// ListenContext is Listen with caller-controlled cancellation:
+// canceling ctx shuts the server down the same way SIGINT/SIGTERM would.
+func (g *Group) ListenContext(ctx context.Context, addr string) error {
+	return g.serve(ctx, addr, "", "")
+}
+
+// ListenTLS is Listen over HTTPS using the given certificate and key.
+func (g *Group) ListenTLS(addr, cert, key string) error {
+	return g.serve(context.Background(), addr, cert, key)
+}
+
+func (g *Group) serve(ctx context.Context, addr, cert, key string) error {
+	ln, err := listen(addr)
+	if err != nil {
+		return err
+	}
+
+	var inFlight sync.WaitGroup
+	gln := &gracefulListener{Listener: ln, inFlight: &inFlight}
+	server := &http.Server{Addr: addr, Handler: g}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		var err error
+		if cert != "" {
+			err = server.ServeTLS(gln, cert, key)
+		} else {
+			err = server.Serve(gln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	logger.Info("listening @" + addr)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR2)
+	defer signal.Stop(sig)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	case s := <-sig:
+		if s == syscall.SIGUSR2 {
+			return g.hotRestart(ln, gln, &inFlight)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-shutdownCtx.Done():
+		return shutdownCtx.Err()
+	}
+}
+
+// hotRestart re-execs the current binary with the listening socket
+// passed down as an extra file descriptor, then waits for this
+// process's own in-flight connections to finish before returning.
+func (g *Group) hotRestart(ln net.Listener, gln *gracefulListener, inFlight *sync.WaitGroup) error {
+	file, err := fileOf(ln)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr, cmd.Stdin = os.Stdout, os.Stderr, os.Stdin
+	cmd.ExtraFiles = []*os.File{file}
+	cmd.Env = append(os.Environ(), listenFDEnv+"=3")
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("hot restart: spawned pid %d, draining this process", cmd.Process.Pid))
+
+	// Stop this process from accepting any more connections; the
+	// listening socket stays open under the child's duplicated fd.
+	gln.stopAccepting()
+	inFlight.Wait()
+	return nil
+}
+
+// listen opens addr for listening, or adopts the listener handed down
+// by a parent process during a hot restart when ROUTER_LISTEN_FD is set.
+func listen(addr string) (net.Listener, error) {
+	if fdStr := os.Getenv(listenFDEnv); fdStr != "" {
+		fd, err := strconv.Atoi(fdStr)
+		if err != nil {
+			return nil, fmt.Errorf("router: invalid %s: %w", listenFDEnv, err)
+		}
+		return net.FileListener(os.NewFile(uintptr(fd), "router-listener"))
+	}
+	return net.Listen("tcp", addr)
+}
+
+// fileOf extracts the underlying *os.File from a net.Listener so it
+// can be passed to a child process via ExtraFiles.
+func fileOf(ln net.Listener) (*os.File, error) {
+	type filer interface {
+		File() (*os.File, error)
+	}
+	f, ok := ln.(filer)
+	if !ok {
+		return nil, errors.New("router: listener does not support hot restart (no File method)")
+	}
+	return f.File()
+}
+
+// gracefulListener wraps a net.Listener to track in-flight connections
+// in a sync.WaitGroup, incrementing on Accept and decrementing when a
+// connection closes, so hot restarts (and shutdown) can wait for them
+// to drain.
+type gracefulListener struct {
+	net.Listener
+	inFlight *sync.WaitGroup
+	once     sync.Once
+}
+
+func (l *gracefulListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.inFlight.Add(1)
+	return &gracefulConn{Conn: conn, inFlight: l.inFlight}, nil
+}
+
+// stopAccepting closes this process's copy of the listening socket's
+// file descriptor so Serve's Accept loop exits; a hot-restarted child
+// holds its own duplicate fd, so the socket keeps listening there.
+func (l *gracefulListener) stopAccepting() {
+	l.once.Do(func() {
+		l.Listener.Close()
+	})
+}
+
+// gracefulConn decrements its listener's in-flight count exactly once,
+// whenever the connection is actually closed.
+type gracefulConn struct {
+	net.Conn
+	inFlight *sync.WaitGroup
+	once     sync.Once
+}
+
+func (c *gracefulConn) Close() error {
+	c.once.Do(c.inFlight.Done)
+	return c.Conn.Close()
+}
@@ -0,0 +1,297 @@
+package router
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/alphastyle/logger"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Options mirrors the cookie attributes net/http.Cookie exposes, kept
+// on the Session so a store can reuse the same settings for every
+// cookie it writes.
+type Options struct {
+	Path     string
+	Domain   string
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// Session is a named bag of values backed by a SessionStore.
+type Session struct {
+	ID      string
+	Values  map[interface{}]interface{}
+	Options *Options
+	IsNew   bool
+
+	name  string
+	store SessionStore
+}
+
+// Save persists the session through the store it was loaded from.
+func (s *Session) Save(c *Context) error {
+	return s.store.Save(c, s)
+}
+
+// SessionStore loads and persists named sessions for a request, modeled
+// on gorilla/sessions.Store.
+type SessionStore interface {
+	Get(c *Context, name string) (*Session, error)
+	New(c *Context, name string) (*Session, error)
+	Save(c *Context, session *Session) error
+}
+
+// sessionWriter defers to the wrapped ResponseWriter but flushes any
+// sessions loaded on its Context before the first byte (or header) is
+// written, so handlers don't have to call Session.Save themselves.
+type sessionWriter struct {
+	http.ResponseWriter
+	c     *Context
+	saved bool
+}
+
+func (w *sessionWriter) saveSessions() {
+	if w.saved {
+		return
+	}
+	w.saved = true
+
+	for _, s := range w.c.sessions {
+		if err := s.Save(w.c); err != nil {
+			logger.Error(err, "session save error")
+		}
+	}
+}
+
+func (w *sessionWriter) WriteHeader(status int) {
+	w.saveSessions()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.saveSessions()
+	return w.ResponseWriter.Write(b)
+}
+
+// Session returns the named session, loading it from the configured
+// store on first access within the request and caching it for later
+// calls and for the auto-save on write.
+func (c *Context) Session(name string) (*Session, error) {
+	if c.store == nil {
+		return nil, errors.New("router: no SessionStore configured, call Group.UseSessionStore first")
+	}
+	if c.sessions == nil {
+		c.sessions = make(map[string]*Session)
+	}
+	if s, ok := c.sessions[name]; ok {
+		return s, nil
+	}
+
+	s, err := c.store.Get(c, name)
+	if err != nil {
+		return s, err
+	}
+	c.sessions[name] = s
+	return s, nil
+}
+
+// NewSession starts (or resets) the named session; it's saved the same
+// way as any other session once the handler writes a response.
+func (c *Context) NewSession(name string) error {
+	if c.store == nil {
+		return errors.New("router: no SessionStore configured, call Group.UseSessionStore first")
+	}
+
+	s, err := c.store.New(c, name)
+	if err != nil {
+		return err
+	}
+	if c.sessions == nil {
+		c.sessions = make(map[string]*Session)
+	}
+	c.sessions[name] = s
+	return nil
+}
+
+// DeleteSession expires the named session's cookie immediately.
+func (c *Context) DeleteSession(name string) error {
+	s, err := c.Session(name)
+	if err != nil {
+		return err
+	}
+	s.Options.MaxAge = -1
+	return s.store.Save(c, s)
+}
+
+// GetSession is a thin wrapper kept for existing callers; new code
+// should call Session directly.
+func (c *Context) GetSession(name string) (*Session, error) {
+	return c.Session(name)
+}
+
+func randomValue() uuid.UUID {
+	return uuid.NewV4()
+}
+
+func newCookie(session *Session, value string) *http.Cookie {
+	opts := session.Options
+	return &http.Cookie{
+		Name:     session.name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   opts.MaxAge,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HttpOnly,
+		SameSite: opts.SameSite,
+	}
+}
+
+// CookieStore keeps the full, authenticated (and optionally encrypted)
+// session payload inside the cookie itself.
+type CookieStore struct {
+	Codecs  []*Codec
+	Options *Options
+}
+
+// NewCookieStore builds a CookieStore from alternating (hashKey,
+// blockKey) pairs; see CodecsFromPairs for key-rotation semantics.
+func NewCookieStore(keyPairs ...[]byte) *CookieStore {
+	return &CookieStore{
+		Codecs:  CodecsFromPairs(keyPairs...),
+		Options: &Options{Path: "/", MaxAge: 30 * 60},
+	}
+}
+
+func (cs *CookieStore) Get(c *Context, name string) (*Session, error) {
+	session, err := cs.New(c, name)
+	if err != nil {
+		return session, err
+	}
+
+	cookie, err := c.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	if err := Decode(name, cookie.Value, &session.Values, cs.Codecs...); err != nil {
+		return session, err
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+func (cs *CookieStore) New(c *Context, name string) (*Session, error) {
+	opts := *cs.Options
+	return &Session{
+		Values:  make(map[interface{}]interface{}),
+		Options: &opts,
+		IsNew:   true,
+		name:    name,
+		store:   cs,
+	}, nil
+}
+
+func (cs *CookieStore) Save(c *Context, session *Session) error {
+	encoded, err := Encode(session.name, session.Values, cs.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(c.ResponseWriter, newCookie(session, encoded))
+	return nil
+}
+
+// FilesystemStore keeps only a signed session ID in the cookie; the
+// actual values are gob-encoded to a file named after that ID.
+type FilesystemStore struct {
+	Codecs  []*Codec
+	Options *Options
+	Path    string
+}
+
+// NewFilesystemStore builds a FilesystemStore rooted at path from
+// alternating (hashKey, blockKey) pairs; see CodecsFromPairs.
+func NewFilesystemStore(path string, keyPairs ...[]byte) *FilesystemStore {
+	return &FilesystemStore{
+		Codecs:  CodecsFromPairs(keyPairs...),
+		Options: &Options{Path: "/", MaxAge: 30 * 60},
+		Path:    path,
+	}
+}
+
+func (fs *FilesystemStore) Get(c *Context, name string) (*Session, error) {
+	session, err := fs.New(c, name)
+	if err != nil {
+		return session, err
+	}
+
+	cookie, err := c.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	if err := Decode(name, cookie.Value, &id, fs.Codecs...); err != nil {
+		return session, err
+	}
+
+	data, err := ioutil.ReadFile(fs.filePath(id))
+	if err != nil {
+		// File missing or expired: fall back to the fresh session from New.
+		return session, nil
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+
+	session.ID = id
+	session.IsNew = false
+	return session, nil
+}
+
+func (fs *FilesystemStore) New(c *Context, name string) (*Session, error) {
+	opts := *fs.Options
+	return &Session{
+		Values:  make(map[interface{}]interface{}),
+		Options: &opts,
+		IsNew:   true,
+		name:    name,
+		store:   fs,
+	}, nil
+}
+
+func (fs *FilesystemStore) Save(c *Context, session *Session) error {
+	if session.ID == "" {
+		session.ID = randomValue().String()
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(fs.Path, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fs.filePath(session.ID), buf.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	encoded, err := Encode(session.name, session.ID, fs.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(c.ResponseWriter, newCookie(session, encoded))
+	return nil
+}
+
+func (fs *FilesystemStore) filePath(id string) string {
+	return filepath.Join(fs.Path, "session_"+id)
+}
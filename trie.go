@@ -0,0 +1,217 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// paramsKey is the context key used to stash matched path parameters
+type paramsKey struct{}
+
+// param is a single matched (name, value) path segment
+type param struct {
+	Key   string
+	Value string
+}
+
+// node is a single segment of the routing trie
+type node struct {
+	children   map[string]*node
+	paramChild *node
+	wildChild  *node
+
+	// handlers is keyed by method, since the trie position alone (and
+	// so paramChild/wildChild) can be shared by routes that name their
+	// captures differently, e.g. "/users/:id" and "/users/:name/profile"
+	// share the same paramChild node at "/users/<capture>". The capture
+	// names for each route are therefore kept per-handler, not on the
+	// shared node.
+	handlers map[string]*routeEntry
+}
+
+// routeEntry is a leaf's registration for one method: the handler plus
+// the ordered capture names ("id", "rest", ...) for that route's
+// pattern, matched positionally against the values walk collects.
+type routeEntry struct {
+	handler    http.HandlerFunc
+	paramNames []string
+}
+
+func newNode() *node {
+	return &node{children: make(map[string]*node)}
+}
+
+// insert registers handler for method at pattern, creating nodes as needed.
+// Patterns are split on "/"; a segment starting with ":" or wrapped in
+// "{}" is a named capture, a segment starting with "*" is a catch-all
+// that must be the last segment.
+func (n *node) insert(method, pattern string, handler http.HandlerFunc) {
+	segments := splitPath(pattern)
+	cur := n
+	var names []string
+	for _, seg := range segments {
+		name, kind := segmentKind(seg)
+		switch kind {
+		case segStatic:
+			child, ok := cur.children[seg]
+			if !ok {
+				child = newNode()
+				cur.children[seg] = child
+			}
+			cur = child
+		case segParam:
+			if cur.paramChild == nil {
+				cur.paramChild = newNode()
+			}
+			cur = cur.paramChild
+			names = append(names, name)
+		case segWild:
+			// a catch-all consumes everything after it, so it's always the leaf
+			if cur.wildChild == nil {
+				cur.wildChild = newNode()
+			}
+			cur = cur.wildChild
+			names = append(names, name)
+		}
+		if kind == segWild {
+			break
+		}
+	}
+	if cur.handlers == nil {
+		cur.handlers = make(map[string]*routeEntry)
+	}
+	cur.handlers[method] = &routeEntry{handler: handler, paramNames: names}
+}
+
+// lookup resolves method and path against the trie. matched reports
+// whether some node matched the path regardless of method, so the
+// caller can tell a 404 (no route) from a 405 (wrong method).
+func (n *node) lookup(method, path string) (handler http.HandlerFunc, params []param, matched bool, allowed []string) {
+	segments := splitPath(path)
+	target, values := n.walk(segments, nil)
+	if target == nil {
+		return nil, nil, false, nil
+	}
+	entry, ok := target.handlers[method]
+	if !ok {
+		allowed = make([]string, 0, len(target.handlers))
+		for m := range target.handlers {
+			allowed = append(allowed, m)
+		}
+		return nil, nil, true, allowed
+	}
+	params = make([]param, len(entry.paramNames))
+	for i, name := range entry.paramNames {
+		params[i] = param{Key: name, Value: values[i]}
+	}
+	return entry.handler, params, true, nil
+}
+
+// walk resolves segments against the trie, returning the matched leaf
+// and the raw captured values in the order they were encountered. The
+// names for those values live on the leaf's routeEntry, not here,
+// since the same trie position can be reached by routes that name
+// their captures differently.
+func (n *node) walk(segments []string, values []string) (*node, []string) {
+	if len(segments) == 0 {
+		if n.handlers != nil {
+			return n, values
+		}
+		// allow a trailing wildcard to match zero remaining segments
+		if n.wildChild != nil {
+			return n.wildChild, append(append([]string{}, values...), "")
+		}
+		return nil, values
+	}
+
+	seg := segments[0]
+	rest := segments[1:]
+
+	// static beats named beats wildcard
+	if child, ok := n.children[seg]; ok {
+		if found, v := child.walk(rest, values); found != nil {
+			return found, v
+		}
+	}
+	if n.paramChild != nil {
+		v := append(append([]string{}, values...), seg)
+		if found, v := n.paramChild.walk(rest, v); found != nil {
+			return found, v
+		}
+	}
+	if n.wildChild != nil {
+		value := strings.Join(segments, "/")
+		v := append(append([]string{}, values...), value)
+		return n.wildChild, v
+	}
+
+	return nil, values
+}
+
+type segKind int
+
+const (
+	segStatic segKind = iota
+	segParam
+	segWild
+)
+
+func segmentKind(seg string) (name string, kind segKind) {
+	switch {
+	case strings.HasPrefix(seg, ":"):
+		return seg[1:], segParam
+	case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+		return seg[1 : len(seg)-1], segParam
+	case strings.HasPrefix(seg, "*"):
+		return seg[1:], segWild
+	default:
+		return seg, segStatic
+	}
+}
+
+// splitPath trims leading/trailing slashes and splits the remainder on "/"
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// withParams stashes the matched path parameters on the request context
+func withParams(r *http.Request, params []param) *http.Request {
+	if len(params) == 0 {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), paramsKey{}, params))
+}
+
+// Params returns all matched path parameters for the current request
+func (c *Context) Params() map[string]string {
+	out := make(map[string]string)
+	if params, ok := c.Request.Context().Value(paramsKey{}).([]param); ok {
+		for _, p := range params {
+			out[p.Key] = p.Value
+		}
+	}
+	return out
+}
+
+// Param returns the value of a matched path parameter, or "" if not present
+func (c *Context) Param(name string) string {
+	if params, ok := c.Request.Context().Value(paramsKey{}).([]param); ok {
+		for _, p := range params {
+			if p.Key == name {
+				return p.Value
+			}
+		}
+	}
+	return ""
+}
+
+// ParamInt returns a matched path parameter parsed as an int
+func (c *Context) ParamInt(name string) (int, error) {
+	return strconv.Atoi(c.Param(name))
+}
@@ -0,0 +1,86 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+)
+
+func noopHandler(http.ResponseWriter, *http.Request) {}
+
+func paramValue(params []param, key string) (string, bool) {
+	for _, p := range params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+func TestNodeLookupExtractsParams(t *testing.T) {
+	root := newNode()
+	root.insert(http.MethodGet, "/users/:id", noopHandler)
+
+	h, params, matched, _ := root.lookup(http.MethodGet, "/users/42")
+	if !matched || h == nil {
+		t.Fatalf("lookup(/users/42) matched = %v, want true with a handler", matched)
+	}
+	if got, ok := paramValue(params, "id"); !ok || got != "42" {
+		t.Fatalf("param %q = %q, %v; want \"42\", true", "id", got, ok)
+	}
+}
+
+func TestNodeLookupNoMatchVsWrongMethod(t *testing.T) {
+	root := newNode()
+	root.insert(http.MethodGet, "/users/:id", noopHandler)
+
+	if _, _, matched, _ := root.lookup(http.MethodGet, "/missing"); matched {
+		t.Fatalf("lookup(/missing) matched = true, want false")
+	}
+
+	_, _, matched, allowed := root.lookup(http.MethodPost, "/users/42")
+	if !matched {
+		t.Fatalf("lookup(POST /users/42) matched = false, want true (path exists, method doesn't)")
+	}
+	if len(allowed) != 1 || allowed[0] != http.MethodGet {
+		t.Fatalf("allowed = %v, want [GET]", allowed)
+	}
+}
+
+// TestNodeInsertDifferentParamNamesAtSameDepth covers the sibling-route
+// regression: "/users/:id" and "/users/:name/profile" share the same
+// paramChild node at "/users/<capture>", so the capture name must be
+// resolved per matched route, not read off the shared node.
+func TestNodeInsertDifferentParamNamesAtSameDepth(t *testing.T) {
+	root := newNode()
+	root.insert(http.MethodGet, "/users/:id", noopHandler)
+	root.insert(http.MethodGet, "/users/:name/profile", noopHandler)
+
+	_, params, matched, _ := root.lookup(http.MethodGet, "/users/123")
+	if !matched {
+		t.Fatalf("lookup(/users/123) matched = false, want true")
+	}
+	if got, ok := paramValue(params, "id"); !ok || got != "123" {
+		t.Fatalf("param %q = %q, %v; want \"123\", true (got sibling route's param name instead)", "id", got, ok)
+	}
+
+	_, params, matched, _ = root.lookup(http.MethodGet, "/users/bob/profile")
+	if !matched {
+		t.Fatalf("lookup(/users/bob/profile) matched = false, want true")
+	}
+	if got, ok := paramValue(params, "name"); !ok || got != "bob" {
+		t.Fatalf("param %q = %q, %v; want \"bob\", true", "name", got, ok)
+	}
+}
+
+func TestNodeLookupWildcard(t *testing.T) {
+	root := newNode()
+	root.insert(http.MethodGet, "/files/*path", noopHandler)
+
+	_, params, matched, _ := root.lookup(http.MethodGet, "/files/a/b/c.txt")
+	if !matched {
+		t.Fatalf("lookup(/files/a/b/c.txt) matched = false, want true")
+	}
+	if got, ok := paramValue(params, "path"); !ok || got != "a/b/c.txt" {
+		t.Fatalf("param %q = %q, %v; want \"a/b/c.txt\", true", "path", got, ok)
+	}
+}